@@ -0,0 +1,48 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package main
+
+import "os"
+
+// Object is one vim25 type: a managed object ("mo"), a data object
+// ("do"), an enumeration ("enum") or a fault ("fault"), depending on
+// Namespace. It's the unit genCode renders through the mo/do/enum/fault
+// templates, whether it was decoded from the hand-curated apiDef JSON
+// or built from a WSDL/XSD by wsdlToObjects.
+type Object struct {
+	Name      string   `json:"name"`
+	Namespace string   `json:"namespace"`
+	Extends   string   `json:"extends"`
+	Fields    []Field  `json:"fields"`
+	Methods   []Method `json:"methods"`
+}
+
+// Field is one struct field of an Object.
+type Field struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Optional bool   `json:"optional"`
+	Slice    bool   `json:"slice"`
+}
+
+// Method is a vim25 operation owned by a managed object, rendered by
+// moTmpl as a func that marshals RequestType and calls
+// soap.Client.Call, returning ReturnValue.
+type Method struct {
+	Name        string `json:"name"`
+	RequestType string `json:"requestType"`
+	ReturnValue string `json:"returnValue"`
+}
+
+// exists reports whether path is present on disk.
+func exists(path string) (bool, error) {
+	_, err := os.Stat(path)
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
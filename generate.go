@@ -124,12 +124,24 @@ func makePublic(field_ string, public bool) string {
 		return field_
 	}
 
+	// Leading underscores (e.g. the WSDL-conventional "_this" parameter
+	// name) can't be cased away, so skip past them to the first letter
+	// that can: "_this" becomes "This", not the still-unexported
+	// "_this".
+	i := 0
+	for i < len(field) && field[i] == '_' {
+		i++
+	}
+	if i == len(field) {
+		return field_
+	}
+
 	if public {
-		field[0] = unicode.ToUpper(field[0])
+		field[i] = unicode.ToUpper(field[i])
 	} else {
-		field[0] = unicode.ToLower(field[0])
+		field[i] = unicode.ToLower(field[i])
 	}
-	return string(field)
+	return string(field[i:])
 }
 
 func comment(text string) string {
@@ -160,6 +172,13 @@ func comment(text string) string {
 	return ""
 }
 
+// tag renders the xml struct tag for a generated field. It's a
+// template func rather than inline backticks in moTmpl/doTmpl/
+// faultTmpl because a raw Go string literal can't contain one.
+func tag(name string) string {
+	return "`xml:\"" + name + "\"`"
+}
+
 //This is how we look for the package
 //or namespace associated to one particular
 //type. This is needed because 4 packages
@@ -202,6 +221,7 @@ var funcMap = template.FuncMap{
 	"makePublic":           makePublic,
 	"comment":              comment,
 	"lookUpNamespace":      lookUpNamespace,
+	"tag":                  tag,
 }
 
 func generate(apiDefFile string) {
@@ -269,24 +289,46 @@ func genCode(objects []Object, mainPkg, tmpl, namespace string) {
 	}
 	defer fd.Close()
 
+	hasObjects, usesTime, hasMethods := false, false, false
+	for _, obj := range objects {
+		if obj.Namespace != namespace {
+			continue
+		}
+		hasObjects = true
+		for _, f := range obj.Fields {
+			if strings.Contains(f.Type, "time.Time") {
+				usesTime = true
+			}
+		}
+		for _, m := range obj.Methods {
+			if m.RequestType != "" && m.ReturnValue != "" {
+				hasMethods = true
+			}
+		}
+	}
+
 	data := new(bytes.Buffer)
 	data.WriteString(headerTmpl)
 	data.WriteString("package " + namespace + "\n")
-	if namespace == "do" {
+	// A namespace with no objects still gets a file (so the package
+	// always exists), but skips the import block: none of its imports
+	// would be used, and an unused import is a build error. "time" and
+	// "soap" are likewise only pulled in when something actually uses
+	// them, since not every batch of objects does.
+	if hasObjects && namespace == "do" && usesTime {
 		data.WriteString(`
 			import (
 				//"github.com/c4milo/govsphere/vim/mo"
 				"time"
 			)
 		`)
-	} else if namespace == "mo" {
-		data.WriteString(`
-			import (
-				"github.com/c4milo/govsphere/vim/do"
-				"time"
-			)
-		`)
-	} else if namespace == "fault" {
+	} else if hasObjects && namespace == "mo" {
+		data.WriteString("\nimport (\n\t\"github.com/c4milo/govsphere/vim/do\"\n")
+		if hasMethods {
+			data.WriteString("\t\"github.com/c4milo/govsphere/vim/soap\"\n")
+		}
+		data.WriteString(")\n")
+	} else if hasObjects && namespace == "fault" {
 		data.WriteString(`
 			import (
 				"github.com/c4milo/govsphere/vim/do"
@@ -0,0 +1,24 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package main
+
+import (
+	"flag"
+	"log"
+)
+
+func main() {
+	apiDefFile := flag.String("apidef", "", "path to a hand-curated JSON API definition")
+	wsdlFile := flag.String("wsdl", "", "path to vSphere's vimService.wsdl")
+	flag.Parse()
+
+	switch {
+	case *wsdlFile != "":
+		generateFromWSDL(*wsdlFile)
+	case *apiDefFile != "":
+		generate(*apiDefFile)
+	default:
+		log.Fatalln("govsphere-generate: one of -wsdl or -apidef must be given")
+	}
+}
@@ -0,0 +1,61 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package main
+
+// headerTmpl is written verbatim at the top of every generated file,
+// ahead of the package clause genCode appends itself.
+const headerTmpl = `// Code generated from the vim25 API definition. DO NOT EDIT.
+
+`
+
+// moTmpl renders a managed object: a thin struct wrapping its
+// ManagedObjectReference plus one method per vim25 operation, each of
+// which marshals its do.* request type and calls soap.Client.Call.
+const moTmpl = `
+{{comment .Name}}
+type {{.Name}} struct {
+	Ref do.ManagedObjectReference
+}
+{{range .Methods}}{{if and .RequestType .ReturnValue}}
+func (m *{{$.Name}}) {{makePublic .Name true}}(client *soap.Client, req *do.{{.RequestType}}) (*do.{{.ReturnValue}}, error) {
+	res := &do.{{.ReturnValue}}{}
+	if _, err := client.Call(req, res, nil); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+{{end}}{{end}}
+`
+
+// doTmpl renders a plain data object: a struct embedding whatever it
+// extends, followed by one field per Field.
+const doTmpl = `
+{{comment .Name}}
+type {{.Name}} struct {
+{{if .Extends}}	{{lookUpNamespace .Extends $.Namespace}}
+{{end}}{{range .Fields}}	{{makePublic .Name true}} {{if .Slice}}[]{{end}}{{lookUpNamespace .Type $.Namespace}} {{tag .Name}}
+{{end}}}
+`
+
+// enumTmpl renders a vim25 enumeration as a named string type with one
+// constant per allowed value.
+const enumTmpl = `
+{{comment .Name}}
+type {{.Name}} string
+
+const (
+{{range .Fields}}	{{$.Name}}{{makePublic .Name true}} {{$.Name}} = "{{.Name}}"
+{{end}})
+`
+
+// faultTmpl renders a SOAP fault detail type the same way doTmpl
+// renders a data object, since faults are just do.* types that show up
+// in a Fault's <detail>.
+const faultTmpl = `
+{{comment .Name}}
+type {{.Name}} struct {
+{{if .Extends}}	{{lookUpNamespace .Extends $.Namespace}}
+{{end}}{{range .Fields}}	{{makePublic .Name true}} {{if .Slice}}[]{{end}}{{lookUpNamespace .Type $.Namespace}} {{tag .Name}}
+{{end}}}
+`
@@ -0,0 +1,213 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package do holds vim25 data objects: the request/response wrapper
+// types vSphere's SOAP API exchanges, as opposed to the managed object
+// types in vim/mo. Most of this package is meant to be produced by the
+// WSDL-driven generator (see generate.go), but the PropertyCollector
+// wire types below are hand-maintained for now since vim/property is
+// built directly on top of them.
+package do
+
+import (
+	"encoding/xml"
+
+	"github.com/c4milo/govsphere/vim/soap"
+)
+
+// ManagedObjectReference is vim25's handle to a managed object: a type
+// name plus an opaque, server-assigned identifier.
+type ManagedObjectReference struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// PropertyChange is one property path and its new value, as returned
+// by RetrievePropertiesEx and WaitForUpdatesEx. Val is polymorphic:
+// vSphere tags it with an xsi:type naming the concrete mo/do type
+// (e.g. "VirtualMachineConfigInfo"), which MarshalXML/UnmarshalXML
+// resolve via soap.Types.
+type PropertyChange struct {
+	Name string
+	Op   string
+	Val  interface{}
+}
+
+// MarshalXML encodes Val using soap.EncodeTyped when its concrete type
+// is registered in soap.Types, so the xsi:type attribute survives the
+// round trip; otherwise Val is encoded plainly.
+func (p PropertyChange) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	if err := e.EncodeElement(p.Name, xml.StartElement{Name: xml.Name{Local: "name"}}); err != nil {
+		return err
+	}
+	if p.Op != "" {
+		if err := e.EncodeElement(p.Op, xml.StartElement{Name: xml.Name{Local: "op"}}); err != nil {
+			return err
+		}
+	}
+	if p.Val != nil {
+		valStart := xml.StartElement{Name: xml.Name{Local: "val"}}
+		if err := soap.EncodeTyped(e, valStart, p.Val, "vim25"); err != nil {
+			if err := e.EncodeElement(p.Val, valStart); err != nil {
+				return err
+			}
+		}
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+// UnmarshalXML decodes Val via soap.DecodeTyped using the val element's
+// xsi:type attribute. A val with no xsi:type, or one naming a type
+// soap.Types doesn't have registered, is skipped rather than failing
+// the decode, matching the forgiving behavior applyChanges already has
+// for properties it can't resolve.
+func (p *PropertyChange) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "name":
+				if err := d.DecodeElement(&p.Name, &t); err != nil {
+					return err
+				}
+			case "op":
+				if err := d.DecodeElement(&p.Op, &t); err != nil {
+					return err
+				}
+			case "val":
+				v, err := soap.DecodeTyped(d, t)
+				if err != nil {
+					if err := d.Skip(); err != nil {
+						return err
+					}
+					continue
+				}
+				p.Val = v
+			default:
+				if err := d.Skip(); err != nil {
+					return err
+				}
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// ObjectSpec names one object a PropertyFilterSpec should watch or
+// retrieve properties from.
+type ObjectSpec struct {
+	Obj  ManagedObjectReference `xml:"obj"`
+	Skip bool                   `xml:"skip,omitempty"`
+}
+
+// PropertySpec names the property paths to retrieve for objects of a
+// given managed object Type.
+type PropertySpec struct {
+	Type    string   `xml:"type"`
+	PathSet []string `xml:"pathSet,omitempty"`
+}
+
+// PropertyFilterSpec is the argument to CreateFilter and
+// RetrievePropertiesEx: which objects, and which of their properties.
+type PropertyFilterSpec struct {
+	ObjectSet []ObjectSpec   `xml:"objectSet"`
+	PropSet   []PropertySpec `xml:"propSet"`
+}
+
+type CreateFilterRequestType struct {
+	This           ManagedObjectReference `xml:"_this"`
+	Spec           PropertyFilterSpec     `xml:"spec"`
+	PartialUpdates bool                   `xml:"partialUpdates"`
+}
+
+type CreateFilterResponse struct {
+	Returnval ManagedObjectReference `xml:"returnval"`
+}
+
+type DestroyPropertyFilterRequestType struct {
+	This ManagedObjectReference `xml:"_this"`
+}
+
+type DestroyPropertyFilterResponse struct{}
+
+// ObjectContent is one object's property values, as returned by
+// RetrievePropertiesEx/ContinueRetrievePropertiesEx.
+type ObjectContent struct {
+	Obj     ManagedObjectReference `xml:"obj"`
+	PropSet []PropertyChange       `xml:"propSet"`
+}
+
+// RetrieveResult is a single page of RetrievePropertiesEx results.
+// Token is non-empty when there's more to fetch via
+// ContinueRetrievePropertiesEx.
+type RetrieveResult struct {
+	Token   string          `xml:"token,omitempty"`
+	Objects []ObjectContent `xml:"objects"`
+}
+
+type RetrievePropertiesExRequestType struct {
+	This    ManagedObjectReference `xml:"_this"`
+	SpecSet []PropertyFilterSpec   `xml:"specSet"`
+}
+
+type RetrievePropertiesExResponse struct {
+	Returnval *RetrieveResult `xml:"returnval"`
+}
+
+type ContinueRetrievePropertiesExRequestType struct {
+	This  ManagedObjectReference `xml:"_this"`
+	Token string                 `xml:"token"`
+}
+
+type ContinueRetrievePropertiesExResponse struct {
+	Returnval *RetrieveResult `xml:"returnval"`
+}
+
+// WaitOptions bounds how long a single WaitForUpdatesEx poll blocks.
+type WaitOptions struct {
+	MaxWaitSeconds int32 `xml:"maxWaitSeconds,omitempty"`
+}
+
+// ObjectUpdate is one object's change set from a WaitForUpdatesEx poll.
+// Kind is "enter", "modify" or "leave".
+type ObjectUpdate struct {
+	Obj       ManagedObjectReference `xml:"obj"`
+	Kind      string                 `xml:"kind"`
+	ChangeSet []PropertyChange       `xml:"changeSet"`
+}
+
+type PropertyFilterUpdate struct {
+	ObjectSet []ObjectUpdate `xml:"objectSet"`
+}
+
+// UpdateSet is one WaitForUpdatesEx poll's worth of changes. Version
+// must be echoed back on the next poll to resume from where this one
+// left off.
+type UpdateSet struct {
+	Version   string                 `xml:"version"`
+	FilterSet []PropertyFilterUpdate `xml:"filterSet"`
+}
+
+type WaitForUpdatesExRequestType struct {
+	This    ManagedObjectReference `xml:"_this"`
+	Version string                 `xml:"version,omitempty"`
+	Options *WaitOptions           `xml:"options,omitempty"`
+}
+
+// WaitForUpdatesExResponse's Returnval is nil when the poll timed out
+// without anything new to report.
+type WaitForUpdatesExResponse struct {
+	Returnval *UpdateSet `xml:"returnval"`
+}
@@ -0,0 +1,61 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package do
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/c4milo/govsphere/vim/soap"
+)
+
+type testConfigInfo struct {
+	Name string `xml:"name"`
+}
+
+func init() {
+	soap.Types.Register("TestConfigInfo", testConfigInfo{})
+}
+
+func TestPropertyChangeRoundTripsTypedVal(t *testing.T) {
+	pc := PropertyChange{Name: "config", Val: &testConfigInfo{Name: "vm1"}}
+
+	data, err := xml.Marshal(pc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded PropertyChange
+	if err := xml.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Name != "config" {
+		t.Errorf("decoded.Name = %q, want config", decoded.Name)
+	}
+
+	info, ok := decoded.Val.(*testConfigInfo)
+	if !ok {
+		t.Fatalf("decoded.Val = %T, want *testConfigInfo", decoded.Val)
+	}
+	if info.Name != "vm1" {
+		t.Errorf("decoded.Val.Name = %q, want vm1", info.Name)
+	}
+}
+
+func TestPropertyChangeWithoutXSITypeDecodesValAsNil(t *testing.T) {
+	const envelope = `<PropertyChange><name>summary</name><val>poweredOn</val></PropertyChange>`
+
+	var decoded PropertyChange
+	if err := xml.Unmarshal([]byte(envelope), &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Name != "summary" {
+		t.Errorf("decoded.Name = %q, want summary", decoded.Name)
+	}
+	if decoded.Val != nil {
+		t.Errorf("decoded.Val = %v, want nil: no xsi:type to resolve it against", decoded.Val)
+	}
+}
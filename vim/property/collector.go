@@ -0,0 +1,229 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package property is a high-level client for vim25's PropertyCollector,
+// built on top of the generated do/mo bindings. It turns CreateFilter +
+// WaitForUpdatesEx into an idiomatic Go streaming API and wraps
+// RetrievePropertiesEx + ContinueRetrievePropertiesEx into a single call
+// that pages until there's nothing left to retrieve. This is the
+// canonical way to observe vSphere inventory.
+package property
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/c4milo/govsphere/vim/do"
+	"github.com/c4milo/govsphere/vim/soap"
+)
+
+// Collector is a client for the PropertyCollector singleton reachable
+// at ServiceContent.PropertyCollector.
+type Collector struct {
+	client *soap.Client
+	ref    do.ManagedObjectReference
+}
+
+// New wraps the PropertyCollector reference ref, reachable via the
+// client's ServiceContent.
+func New(client *soap.Client, ref do.ManagedObjectReference) *Collector {
+	return &Collector{client: client, ref: ref}
+}
+
+// Update is one object's worth of property changes from a single
+// WaitForUpdatesEx poll.
+type Update struct {
+	Obj     do.ManagedObjectReference
+	Kind    string
+	Changes []do.PropertyChange
+}
+
+// Watch creates a PropertyFilterSpec rooted at root for the given
+// property paths and streams Updates until ctx is canceled, managing
+// the version token and maxWaitSeconds itself. The update channel is
+// closed when watching stops; the error channel receives at most one
+// value (the reason) before also closing.
+func (c *Collector) Watch(ctx context.Context, root do.ManagedObjectReference, props []string, maxWaitSeconds int32) (<-chan Update, <-chan error, error) {
+	filter, err := c.createFilter(ctx, root, props)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	updates := make(chan Update)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(updates)
+		defer close(errc)
+		defer c.destroyFilter(context.Background(), filter)
+
+		var version string
+		for {
+			req := &do.WaitForUpdatesExRequestType{
+				This:    c.ref,
+				Version: version,
+				Options: &do.WaitOptions{MaxWaitSeconds: maxWaitSeconds},
+			}
+
+			res := &do.WaitForUpdatesExResponse{}
+			if _, err := c.client.CallContext(ctx, req, res, nil); err != nil {
+				errc <- err
+				return
+			}
+
+			// A nil UpdateSet means the poll timed out with nothing
+			// new; keep the same version and try again.
+			if res.Returnval == nil {
+				continue
+			}
+
+			version = res.Returnval.Version
+			for _, filterUpdate := range res.Returnval.FilterSet {
+				for _, objUpdate := range filterUpdate.ObjectSet {
+					select {
+					case updates <- Update{
+						Obj:     objUpdate.Obj,
+						Kind:    objUpdate.Kind,
+						Changes: objUpdate.ChangeSet,
+					}:
+					case <-ctx.Done():
+						errc <- ctx.Err()
+						return
+					}
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				errc <- ctx.Err()
+				return
+			default:
+			}
+		}
+	}()
+
+	return updates, errc, nil
+}
+
+func (c *Collector) createFilter(ctx context.Context, root do.ManagedObjectReference, props []string) (do.ManagedObjectReference, error) {
+	req := &do.CreateFilterRequestType{
+		This: c.ref,
+		Spec: do.PropertyFilterSpec{
+			ObjectSet: []do.ObjectSpec{{Obj: root}},
+			PropSet:   []do.PropertySpec{{Type: root.Type, PathSet: props}},
+		},
+		PartialUpdates: true,
+	}
+
+	res := &do.CreateFilterResponse{}
+	if _, err := c.client.CallContext(ctx, req, res, nil); err != nil {
+		return do.ManagedObjectReference{}, err
+	}
+	return res.Returnval, nil
+}
+
+func (c *Collector) destroyFilter(ctx context.Context, filter do.ManagedObjectReference) {
+	req := &do.DestroyPropertyFilterRequestType{This: filter}
+	c.client.CallContext(ctx, req, &do.DestroyPropertyFilterResponse{}, nil)
+}
+
+// Retrieve fills out, a pointer to a mo.* struct, with the requested
+// properties of ref, paging through ContinueRetrievePropertiesEx until
+// the returned token is exhausted.
+func (c *Collector) Retrieve(ctx context.Context, ref do.ManagedObjectReference, props []string, out interface{}) error {
+	req := &do.RetrievePropertiesExRequestType{
+		This: c.ref,
+		SpecSet: []do.PropertyFilterSpec{{
+			ObjectSet: []do.ObjectSpec{{Obj: ref}},
+			PropSet:   []do.PropertySpec{{Type: ref.Type, PathSet: props}},
+		}},
+	}
+
+	res := &do.RetrievePropertiesExResponse{}
+	if _, err := c.client.CallContext(ctx, req, res, nil); err != nil {
+		return err
+	}
+	if res.Returnval == nil || len(res.Returnval.Objects) == 0 {
+		return fmt.Errorf("property: %s %q not found", ref.Type, ref.Value)
+	}
+
+	changes := append([]do.PropertyChange{}, res.Returnval.Objects[0].PropSet...)
+	token := res.Returnval.Token
+
+	for token != "" {
+		contReq := &do.ContinueRetrievePropertiesExRequestType{This: c.ref, Token: token}
+		contRes := &do.ContinueRetrievePropertiesExResponse{}
+		if _, err := c.client.CallContext(ctx, contReq, contRes, nil); err != nil {
+			return err
+		}
+		if contRes.Returnval == nil {
+			break
+		}
+
+		for _, obj := range contRes.Returnval.Objects {
+			changes = append(changes, obj.PropSet...)
+		}
+		token = contRes.Returnval.Token
+	}
+
+	return applyChanges(changes, out)
+}
+
+// applyChanges copies each PropertyChange into the matching exported
+// field of out by name, following dotted paths (e.g.
+// "config.hardware.device", which PropertyCollector returns far more
+// often than top-level names) into nested structs. It's intentionally
+// forgiving: properties the caller didn't ask mo.* to expose, or whose
+// type vSphere didn't xsi:type in a way DecodeTyped could resolve, are
+// skipped rather than failing the whole Retrieve.
+func applyChanges(changes []do.PropertyChange, out interface{}) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("property: out must be a pointer to a struct, got %T", out)
+	}
+
+	for _, change := range changes {
+		field, ok := fieldByPath(v.Elem(), change.Name)
+		if !ok || !field.CanSet() {
+			continue
+		}
+
+		val := reflect.ValueOf(change.Val)
+		if val.IsValid() && val.Type().AssignableTo(field.Type()) {
+			field.Set(val)
+		}
+	}
+
+	return nil
+}
+
+// fieldByPath resolves a dotted vim25 property path against v's
+// exported fields by walking one struct level per path segment, the
+// way vSphere's nested property names mirror nested mo.* structs. Nil
+// pointers encountered along the way are allocated so a deeply nested
+// path can still be set.
+func fieldByPath(v reflect.Value, path string) (reflect.Value, bool) {
+	for _, name := range strings.Split(path, ".") {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, false
+		}
+
+		v = v.FieldByName(strings.Title(name))
+		if !v.IsValid() {
+			return reflect.Value{}, false
+		}
+	}
+	return v, true
+}
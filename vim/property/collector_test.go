@@ -0,0 +1,144 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package property
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/c4milo/govsphere/vim/do"
+	"github.com/c4milo/govsphere/vim/soap"
+)
+
+const retrievePropertiesExFirstPage = `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">
+<soapenv:Body>
+<RetrievePropertiesExResponse>
+<returnval>
+<token>page2</token>
+<objects><obj type="VirtualMachine">vm-1</obj></objects>
+</returnval>
+</RetrievePropertiesExResponse>
+</soapenv:Body>
+</soapenv:Envelope>`
+
+const continueRetrievePropertiesExLastPage = `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">
+<soapenv:Body>
+<ContinueRetrievePropertiesExResponse>
+<returnval>
+<objects><obj type="VirtualMachine">vm-1</obj></objects>
+</returnval>
+</ContinueRetrievePropertiesExResponse>
+</soapenv:Body>
+</soapenv:Envelope>`
+
+// TestRetrievePagesUntilTokenExhausted checks that Retrieve keeps
+// calling ContinueRetrievePropertiesEx while a token is returned, and
+// stops once a page comes back without one.
+func TestRetrievePagesUntilTokenExhausted(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "text/xml")
+		w.WriteHeader(http.StatusOK)
+		if n == 1 {
+			w.Write([]byte(retrievePropertiesExFirstPage))
+			return
+		}
+		w.Write([]byte(continueRetrievePropertiesExLastPage))
+	}))
+	defer server.Close()
+
+	client := soap.NewClient(server.URL, "urn:vim25/RetrievePropertiesEx", false)
+	c := New(client, do.ManagedObjectReference{Type: "PropertyCollector", Value: "propertyCollector"})
+
+	var out struct{}
+	ref := do.ManagedObjectReference{Type: "VirtualMachine", Value: "vm-1"}
+	if err := c.Retrieve(context.Background(), ref, []string{"name"}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if atomic.LoadInt32(&requests) != 2 {
+		t.Fatalf("requests = %d, want 2 (the initial page plus one continuation)", requests)
+	}
+}
+
+// TestRetrieveNotFound checks that an empty first page is reported as
+// an error rather than silently returning a zero-valued out.
+func TestRetrieveNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">
+<soapenv:Body><RetrievePropertiesExResponse></RetrievePropertiesExResponse></soapenv:Body>
+</soapenv:Envelope>`))
+	}))
+	defer server.Close()
+
+	client := soap.NewClient(server.URL, "urn:vim25/RetrievePropertiesEx", false)
+	c := New(client, do.ManagedObjectReference{Type: "PropertyCollector", Value: "propertyCollector"})
+
+	var out struct{}
+	ref := do.ManagedObjectReference{Type: "VirtualMachine", Value: "vm-1"}
+	if err := c.Retrieve(context.Background(), ref, []string{"name"}, &out); err == nil {
+		t.Fatal("expected an error when the object isn't found")
+	}
+}
+
+type fakeHardware struct {
+	Device string
+}
+
+type fakeConfig struct {
+	Hardware *fakeHardware
+}
+
+type fakeVM struct {
+	Name   string
+	Config *fakeConfig
+}
+
+func TestApplyChangesTopLevelField(t *testing.T) {
+	var out fakeVM
+	changes := []do.PropertyChange{{Name: "name", Val: "vm1"}}
+
+	if err := applyChanges(changes, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Name != "vm1" {
+		t.Errorf("out.Name = %q, want vm1", out.Name)
+	}
+}
+
+func TestApplyChangesDottedPath(t *testing.T) {
+	var out fakeVM
+	changes := []do.PropertyChange{{Name: "config.hardware.device", Val: "scsi0:0"}}
+
+	if err := applyChanges(changes, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Config == nil || out.Config.Hardware == nil {
+		t.Fatal("expected applyChanges to allocate Config and Config.Hardware along the path")
+	}
+	if out.Config.Hardware.Device != "scsi0:0" {
+		t.Errorf("out.Config.Hardware.Device = %q, want scsi0:0", out.Config.Hardware.Device)
+	}
+}
+
+func TestApplyChangesUnresolvablePathIsSkipped(t *testing.T) {
+	var out fakeVM
+	changes := []do.PropertyChange{{Name: "summary.runtime.powerState", Val: "poweredOn"}}
+
+	if err := applyChanges(changes, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Config != nil {
+		t.Errorf("out.Config = %+v, want unset: \"summary\" isn't a field of fakeVM", out.Config)
+	}
+}
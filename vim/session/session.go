@@ -0,0 +1,181 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package session layers vim25 session management on top of
+// soap.Client: a cookie jar so callers stop threading []*http.Cookie
+// through every Call, a background keep-alive heartbeat, and
+// transparent re-login when the server reports the session expired.
+package session
+
+import (
+	"context"
+	"net/http/cookiejar"
+	"sync"
+	"time"
+
+	"github.com/c4milo/govsphere/vim/soap"
+)
+
+// defaultKeepAliveInterval is how often Session pings the server to
+// keep the session alive. vSphere expires idle sessions after 30
+// minutes, so 10 leaves comfortable margin.
+const defaultKeepAliveInterval = 10 * time.Minute
+
+// notAuthenticatedFault is the detail type name vSphere uses on the
+// SOAP fault it returns once a session has expired or was never
+// established.
+const notAuthenticatedFault = "NotAuthenticatedFault"
+
+// LoginFunc establishes a vim25 session on client, e.g. by calling
+// ServiceInstance.Login with a username and password, or by exchanging
+// a SAML token for SSO. It's also used to re-authenticate after the
+// session expires, so implementations should be safe to call more than
+// once.
+type LoginFunc func(ctx context.Context, client *soap.Client) error
+
+// LogoutFunc tears down the session established by a LoginFunc,
+// typically SessionManager.Logout.
+type LogoutFunc func(ctx context.Context, client *soap.Client) error
+
+// KeepAliveFunc pings an established session, typically
+// SessionManager.SessionIsActive. Session treats a NotAuthenticatedFault
+// returned from it as a signal to re-login.
+type KeepAliveFunc func(ctx context.Context, client *soap.Client) error
+
+// Session wraps a *soap.Client with the session bookkeeping vSphere
+// requires: cookies, a keep-alive heartbeat and re-login.
+type Session struct {
+	Client *soap.Client
+
+	login     LoginFunc
+	logout    LogoutFunc
+	keepAlive KeepAliveFunc
+	interval  time.Duration
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New wraps client with session management, installing a fresh cookie
+// jar on it so the vim25 session cookie is tracked automatically
+// instead of being threaded through every Call by hand.
+func New(client *soap.Client, login LoginFunc, logout LogoutFunc, keepAlive KeepAliveFunc) (*Session, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	client.HTTPClient().Jar = jar
+
+	return &Session{
+		Client:    client,
+		login:     login,
+		logout:    logout,
+		keepAlive: keepAlive,
+		interval:  defaultKeepAliveInterval,
+	}, nil
+}
+
+// SetKeepAliveInterval overrides the default 10 minute heartbeat. It
+// only has an effect if called before Login starts the background
+// goroutine.
+func (sess *Session) SetKeepAliveInterval(d time.Duration) {
+	sess.interval = d
+}
+
+// Login authenticates via the configured LoginFunc and starts the
+// keep-alive goroutine.
+func (sess *Session) Login(ctx context.Context) error {
+	if err := sess.login(ctx, sess.Client); err != nil {
+		return err
+	}
+
+	sess.startKeepAlive()
+	return nil
+}
+
+// Logout calls the configured LogoutFunc and stops the keep-alive
+// goroutine. Close is still safe to call (or defer) afterwards.
+func (sess *Session) Logout(ctx context.Context) error {
+	defer sess.Close()
+
+	if sess.logout == nil {
+		return nil
+	}
+	return sess.logout(ctx, sess.Client)
+}
+
+// Close stops the keep-alive goroutine without logging out
+// server-side. It's safe to call multiple times and whether or not
+// Login ever ran.
+func (sess *Session) Close() {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	if sess.stop == nil {
+		return
+	}
+	close(sess.stop)
+	<-sess.done
+	sess.stop, sess.done = nil, nil
+}
+
+// CallContext performs request/response through the wrapped
+// soap.Client. If the server reports the session has expired, it
+// transparently re-logs in via the configured LoginFunc and retries the
+// request once before giving up.
+func (sess *Session) CallContext(ctx context.Context, request, response interface{}) error {
+	_, err := sess.Client.CallContext(ctx, request, response, nil)
+	if !isNotAuthenticated(err) {
+		return err
+	}
+
+	if err := sess.login(ctx, sess.Client); err != nil {
+		return err
+	}
+
+	_, err = sess.Client.CallContext(ctx, request, response, nil)
+	return err
+}
+
+func (sess *Session) startKeepAlive() {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	if sess.stop != nil {
+		return
+	}
+	sess.stop = make(chan struct{})
+	sess.done = make(chan struct{})
+
+	go sess.keepAliveLoop(sess.stop, sess.done)
+}
+
+func (sess *Session) keepAliveLoop(stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(sess.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), sess.interval)
+			err := sess.keepAlive(ctx, sess.Client)
+			if isNotAuthenticated(err) {
+				// Best effort: if re-login fails here, the next tick
+				// will notice the session is still down and try again.
+				_ = sess.login(ctx, sess.Client)
+			}
+			cancel()
+		}
+	}
+}
+
+func isNotAuthenticated(err error) bool {
+	fault, ok := err.(*soap.SoapFault)
+	return ok && fault.DetailType == notAuthenticatedFault
+}
@@ -0,0 +1,175 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package session
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/c4milo/govsphere/vim/soap"
+)
+
+const notAuthenticatedFaultEnvelope = `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">
+<soapenv:Body>
+<soapenv:Fault>
+<faultcode>ServerFaultCode</faultcode>
+<faultstring>The session is not authenticated.</faultstring>
+<detail><NotAuthenticatedFault xmlns="urn:vim25"></NotAuthenticatedFault></detail>
+</soapenv:Fault>
+</soapenv:Body>
+</soapenv:Envelope>`
+
+const okEnvelope = `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">
+<soapenv:Body><FooResponse></FooResponse></soapenv:Body>
+</soapenv:Envelope>`
+
+func TestIsNotAuthenticated(t *testing.T) {
+	authFault := &soap.SoapFault{DetailType: notAuthenticatedFault}
+	if !isNotAuthenticated(authFault) {
+		t.Error("expected a NotAuthenticatedFault to be recognized")
+	}
+
+	otherFault := &soap.SoapFault{DetailType: "InvalidLoginFault"}
+	if isNotAuthenticated(otherFault) {
+		t.Error("did not expect an InvalidLoginFault to be recognized as NotAuthenticatedFault")
+	}
+
+	if isNotAuthenticated(nil) {
+		t.Error("did not expect a nil error to be recognized as NotAuthenticatedFault")
+	}
+}
+
+func TestSessionLoginStartsKeepAliveAndClose(t *testing.T) {
+	client := soap.NewClient("https://vsphere.example.com/sdk", "urn:vim25/Login", false)
+
+	var loginCalls, logoutCalls int32
+	login := func(ctx context.Context, c *soap.Client) error {
+		atomic.AddInt32(&loginCalls, 1)
+		return nil
+	}
+	logout := func(ctx context.Context, c *soap.Client) error {
+		atomic.AddInt32(&logoutCalls, 1)
+		return nil
+	}
+	keepAlive := func(ctx context.Context, c *soap.Client) error { return nil }
+
+	sess, err := New(client, login, logout, keepAlive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess.SetKeepAliveInterval(time.Hour)
+
+	if err := sess.Login(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&loginCalls) != 1 {
+		t.Fatalf("loginCalls = %d, want 1", loginCalls)
+	}
+	sess.mu.Lock()
+	started := sess.stop != nil
+	sess.mu.Unlock()
+	if !started {
+		t.Fatal("expected Login to start the keep-alive goroutine")
+	}
+
+	if err := sess.Logout(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&logoutCalls) != 1 {
+		t.Fatalf("logoutCalls = %d, want 1", logoutCalls)
+	}
+
+	// Close must be idempotent and safe after Logout already called it.
+	sess.Close()
+}
+
+func TestSessionKeepAliveReLogsInOnNotAuthenticated(t *testing.T) {
+	client := soap.NewClient("https://vsphere.example.com/sdk", "urn:vim25/Login", false)
+
+	var loginCalls int32
+	login := func(ctx context.Context, c *soap.Client) error {
+		atomic.AddInt32(&loginCalls, 1)
+		return nil
+	}
+	keepAlive := func(ctx context.Context, c *soap.Client) error {
+		return &soap.SoapFault{DetailType: notAuthenticatedFault}
+	}
+
+	sess, err := New(client, login, nil, keepAlive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess.SetKeepAliveInterval(10 * time.Millisecond)
+	defer sess.Close()
+
+	if err := sess.Login(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		// Login is called once up front, and again by the keep-alive
+		// loop once it notices the session is down.
+		if atomic.LoadInt32(&loginCalls) >= 2 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("loginCalls = %d after 1s, want at least 2", loginCalls)
+}
+
+func TestCallContextReLogsInOnceAndRetries(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "text/xml")
+		if n == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(notAuthenticatedFaultEnvelope))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(okEnvelope))
+	}))
+	defer server.Close()
+
+	client := soap.NewClient(server.URL, "urn:vim25/Foo", false)
+
+	var loginCalls int32
+	login := func(ctx context.Context, c *soap.Client) error {
+		atomic.AddInt32(&loginCalls, 1)
+		return nil
+	}
+
+	sess, err := New(client, login, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.Close()
+
+	type fooRequest struct {
+		XMLName struct{} `xml:"Foo"`
+	}
+	type fooResponse struct {
+		XMLName struct{} `xml:"FooResponse"`
+	}
+
+	var resp fooResponse
+	if err := sess.CallContext(context.Background(), &fooRequest{}, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if atomic.LoadInt32(&loginCalls) != 1 {
+		t.Fatalf("loginCalls = %d, want 1", loginCalls)
+	}
+	if atomic.LoadInt32(&requests) != 2 {
+		t.Fatalf("requests = %d, want 2 (the failed call plus the retry)", requests)
+	}
+}
@@ -0,0 +1,79 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package soap
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// Fault mirrors the SOAP 1.1 Fault element. Field names follow the
+// spec's lowercase element names via xml tags rather than matching
+// them, since encoding/xml can't populate unexported fields.
+type Fault struct {
+	XMLName     xml.Name    `xml:"http://schemas.xmlsoap.org/soap/envelope/ Fault"`
+	FaultCode   string      `xml:"faultcode"`
+	FaultString string      `xml:"faultstring"`
+	FaultActor  string      `xml:"faultactor"`
+	Detail      FaultDetail `xml:"detail"`
+}
+
+// FaultDetail wraps the <detail> element of a vSphere SOAP fault. Its
+// content varies per fault type (NotAuthenticatedFault,
+// InvalidLoginFault, and so on) so it's captured generically: TypeName
+// preserves the concrete fault type for callers that want to
+// type-switch, and RawXML lets them unmarshal it into the matching
+// generated fault.* type.
+type FaultDetail struct {
+	XMLName xml.Name `xml:"detail"`
+	Fault   struct {
+		XMLName xml.Name
+		RawXML  []byte `xml:",innerxml"`
+	} `xml:",any"`
+}
+
+// SoapFault is the error Call/CallContext return whenever a response
+// carries a SOAP Fault. It implements error and preserves enough of
+// the original fault to let callers recover the vSphere-specific
+// detail type.
+type SoapFault struct {
+	Code       string
+	Message    string
+	Actor      string
+	DetailType string
+	DetailXML  []byte
+}
+
+func newSoapFault(f *Fault) *SoapFault {
+	return &SoapFault{
+		Code:       f.FaultCode,
+		Message:    f.FaultString,
+		Actor:      f.FaultActor,
+		DetailType: f.Detail.Fault.XMLName.Local,
+		DetailXML:  f.Detail.Fault.RawXML,
+	}
+}
+
+func (f *SoapFault) Error() string {
+	if f.DetailType != "" {
+		return fmt.Sprintf("soap fault %s (%s): %s", f.Code, f.DetailType, f.Message)
+	}
+	return fmt.Sprintf("soap fault %s: %s", f.Code, f.Message)
+}
+
+// Decode unmarshals the fault's <detail> content into detail, which
+// callers should pass as a pointer to the generated fault.* type named
+// by DetailType. DetailXML only holds the inner content of the fault
+// element inside <detail> (that element's own opening/closing tags are
+// stripped by the ,innerxml capture in FaultDetail), so Decode rewraps
+// it with a synthetic root
+// before unmarshalling; otherwise detail's fields, which match on
+// child element names, would have nothing to match against.
+func (f *SoapFault) Decode(detail interface{}) error {
+	if len(f.DetailXML) == 0 {
+		return nil
+	}
+	wrapped := "<" + f.DetailType + ">" + string(f.DetailXML) + "</" + f.DetailType + ">"
+	return xml.Unmarshal([]byte(wrapped), detail)
+}
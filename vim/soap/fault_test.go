@@ -0,0 +1,83 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package soap
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+const faultXML = `<soapenv:Fault xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">
+	<faultcode>ServerFaultCode</faultcode>
+	<faultstring>Login failure</faultstring>
+	<faultactor>vpxd</faultactor>
+	<detail>
+		<InvalidLoginFault xmlns="urn:vim25">
+			<reason>Invalid credentials</reason>
+		</InvalidLoginFault>
+	</detail>
+</soapenv:Fault>`
+
+func TestFaultUnmarshal(t *testing.T) {
+	var f Fault
+	if err := xml.Unmarshal([]byte(faultXML), &f); err != nil {
+		t.Fatal(err)
+	}
+
+	if f.FaultCode != "ServerFaultCode" {
+		t.Errorf("FaultCode = %q, want ServerFaultCode", f.FaultCode)
+	}
+	if f.FaultString != "Login failure" {
+		t.Errorf("FaultString = %q, want Login failure", f.FaultString)
+	}
+	if f.Detail.Fault.XMLName.Local != "InvalidLoginFault" {
+		t.Errorf("Detail type = %q, want InvalidLoginFault", f.Detail.Fault.XMLName.Local)
+	}
+}
+
+func TestNewSoapFaultAndError(t *testing.T) {
+	var f Fault
+	if err := xml.Unmarshal([]byte(faultXML), &f); err != nil {
+		t.Fatal(err)
+	}
+
+	soapErr := newSoapFault(&f)
+	if soapErr.DetailType != "InvalidLoginFault" {
+		t.Errorf("DetailType = %q, want InvalidLoginFault", soapErr.DetailType)
+	}
+
+	msg := soapErr.Error()
+	if !strings.Contains(msg, "ServerFaultCode") || !strings.Contains(msg, "InvalidLoginFault") || !strings.Contains(msg, "Login failure") {
+		t.Errorf("Error() = %q, missing expected fields", msg)
+	}
+}
+
+func TestSoapFaultDecode(t *testing.T) {
+	var f Fault
+	if err := xml.Unmarshal([]byte(faultXML), &f); err != nil {
+		t.Fatal(err)
+	}
+
+	soapErr := newSoapFault(&f)
+
+	var detail struct {
+		Reason string `xml:"reason"`
+	}
+	if err := soapErr.Decode(&detail); err != nil {
+		t.Fatal(err)
+	}
+	if detail.Reason != "Invalid credentials" {
+		t.Errorf("detail.Reason = %q, want %q", detail.Reason, "Invalid credentials")
+	}
+}
+
+func TestSoapFaultDecodeWithoutDetail(t *testing.T) {
+	soapErr := &SoapFault{Code: "Client", Message: "bad request"}
+
+	var detail struct{}
+	if err := soapErr.Decode(&detail); err != nil {
+		t.Fatalf("Decode with no detail xml should be a no-op, got %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package soap
+
+import (
+	"log"
+	"net/http"
+	"regexp"
+)
+
+// Logger is the interface soap.Client uses to report request and
+// response activity. Callers inject their own implementation via
+// Client.SetLogger; the default is a no-op so using the client doesn't
+// require wiring up logging.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+type noopLogger struct{}
+
+func (noopLogger) Debugf(string, ...interface{}) {}
+func (noopLogger) Infof(string, ...interface{})  {}
+func (noopLogger) Warnf(string, ...interface{})  {}
+func (noopLogger) Errorf(string, ...interface{}) {}
+
+// stdLogger adapts the standard library's log package to Logger.
+type stdLogger struct {
+	*log.Logger
+}
+
+func (l stdLogger) Debugf(format string, args ...interface{}) { l.Printf("DEBUG "+format, args...) }
+func (l stdLogger) Infof(format string, args ...interface{})  { l.Printf("INFO "+format, args...) }
+func (l stdLogger) Warnf(format string, args ...interface{})  { l.Printf("WARN "+format, args...) }
+func (l stdLogger) Errorf(format string, args ...interface{}) { l.Printf("ERROR "+format, args...) }
+
+// NewStdLogger adapts l to the Logger interface, for callers who just
+// want Client activity routed through the standard library logger.
+func NewStdLogger(l *log.Logger) Logger {
+	return stdLogger{l}
+}
+
+const redactedPlaceholder = "***REDACTED***"
+
+// sensitiveElements matches the XML elements vSphere uses to carry
+// credentials and session identifiers, with or without the namespace
+// prefix generated/marshalled requests qualify them with (e.g.
+// <ns1:password>...</ns1:password>).
+var sensitiveElements = regexp.MustCompile(`(?is)<((?:\w+:)?(?:password|sessionKey))([^>]*)>.*?</(?:\w+:)?(?:password|sessionKey)>`)
+
+// redact scrubs credentials and session identifiers out of a SOAP
+// envelope before it's handed to a Logger at the default (non-trace)
+// level.
+func redact(envelope string) string {
+	return sensitiveElements.ReplaceAllString(envelope, "<$1$2>"+redactedPlaceholder+"</$1>")
+}
+
+// redactCookies renders cookies as name=value pairs for logging with
+// every value scrubbed, since vSphere session cookies are credentials.
+func redactCookies(cookies []*http.Cookie) []string {
+	out := make([]string, len(cookies))
+	for i, c := range cookies {
+		out[i] = c.Name + "=" + redactedPlaceholder
+	}
+	return out
+}
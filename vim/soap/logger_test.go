@@ -0,0 +1,41 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package soap
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+	}{
+		{"unqualified password", `<password>hunter2</password>`},
+		{"unqualified sessionKey", `<sessionKey>abc123</sessionKey>`},
+		{"namespace-qualified password", `<ns1:password>hunter2</ns1:password>`},
+		{"namespace-qualified sessionKey", `<vim25:sessionKey>abc123</vim25:sessionKey>`},
+		{"password with attributes", `<password xsi:type="xsd:string">hunter2</password>`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			out := redact(c.in)
+			if out == c.in {
+				t.Fatalf("redact(%q) did not scrub the sensitive element", c.in)
+			}
+			if strings.Contains(out, "hunter2") || strings.Contains(out, "abc123") {
+				t.Fatalf("redact(%q) = %q, still contains the original value", c.in, out)
+			}
+		})
+	}
+}
+
+func TestRedactLeavesOtherElementsAlone(t *testing.T) {
+	in := `<username>alice</username>`
+	if out := redact(in); out != in {
+		t.Fatalf("redact(%q) = %q, want unchanged", in, out)
+	}
+}
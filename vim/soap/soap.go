@@ -5,62 +5,186 @@ package soap
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/xml"
+	"fmt"
 	"io/ioutil"
-	"log"
-	"net"
 	"net/http"
-	"net/http/httputil"
 	"time"
 )
 
 type Envelope struct {
-	XMLName       xml.Name `xml:"http://schemas.xmlsoap.org/soap/envelope/ Envelope"`
-	EncodingStyle string   `xml:"http://schemas.xmlsoap.org/soap/encoding/ encodingStyle,attr"`
-	Header        Header   `xml:"http://schemas.xmlsoap.org/soap/envelope/ Header"`
-	Body          Body     `xml:"http://schemas.xmlsoap.org/soap/envelope/ Body"`
+	XMLName xml.Name `xml:"http://schemas.xmlsoap.org/soap/envelope/ Envelope"`
+	// XSINamespace/XSDNamespace/Vim25Namespace register the xsi, xsd and
+	// vim25 prefixes once on the envelope itself, rather than repeating
+	// them on every xsi:type attribute generated types emit via
+	// EncodeTyped.
+	XSINamespace   string `xml:"xmlns:xsi,attr"`
+	XSDNamespace   string `xml:"xmlns:xsd,attr"`
+	Vim25Namespace string `xml:"xmlns:vim25,attr"`
+	EncodingStyle  string `xml:"http://schemas.xmlsoap.org/soap/encoding/ encodingStyle,attr"`
+	Header         Header `xml:"http://schemas.xmlsoap.org/soap/envelope/ Header"`
+	Body           Body   `xml:"http://schemas.xmlsoap.org/soap/envelope/ Body"`
 }
 
+// xsdNamespaceURI/vim25NamespaceURI accompany xsiNamespace (declared in
+// xsitype.go) as the namespace prefixes every envelope declares once,
+// instead of repeating them on individual xsi:type attributes.
+const (
+	xsdNamespaceURI   = "http://www.w3.org/2001/XMLSchema"
+	vim25NamespaceURI = "urn:vim25"
+)
+
 type Header struct {
 	Header interface{}
 }
 
 type Body struct {
-	Fault *Fault `xml:"fault,omitempty"` //It has to be a pointer or omitempty doesn't work
+	// Fault has to be a pointer for omitempty to work, and needs the
+	// SOAP envelope namespace to actually match the response element.
+	Fault *Fault `xml:"http://schemas.xmlsoap.org/soap/envelope/ Fault,omitempty"`
 	Body  string `xml:",innerxml"`
 }
 
-type Fault struct {
-	faultcode   string `xml:"http://schemas.xmlsoap.org/soap/envelope/ faultcode"`
-	faultstring string `xml:"faultstring"`
-	faultactor  string `xml:"faultactor"`
-	detail      string `xml:"detail"`
+const (
+	// defaultTimeout is the overall request deadline Call applies.
+	// CallContext uses only whatever deadline the caller's context has.
+	defaultTimeout = 30 * time.Second
+
+	// defaultMaxRetries is how many additional attempts Call makes after
+	// the initial one, for transient network errors and 5xx responses.
+	defaultMaxRetries = 2
+
+	// defaultBackoff is the base delay used to compute the exponential
+	// backoff between retries: attempt N waits defaultBackoff * 2^(N-1).
+	defaultBackoff = 500 * time.Millisecond
+)
+
+// RetryPolicy controls how Call retries transient failures.
+type RetryPolicy struct {
+	// MaxRetries is the number of attempts after the first one. Zero
+	// disables retrying altogether.
+	MaxRetries int
+	// BaseDelay is the delay before the first retry. Subsequent retries
+	// double it, up to MaxDelay.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff delay. Zero means no cap.
+	MaxDelay time.Duration
 }
 
 type Client struct {
 	url, soapAction string
 	tls             bool
+
+	httpClient *http.Client
+	timeout    time.Duration
+	retry      RetryPolicy
+
+	logger Logger
+	trace  bool
 }
 
-func NewClient(url, soapAction string, tls bool) *Client {
-	return &Client{
+func NewClient(url, soapAction string, insecureTLS bool) *Client {
+	c := &Client{
 		url:        url,
 		soapAction: soapAction,
-		tls:        tls,
+		tls:        insecureTLS,
+		timeout:    defaultTimeout,
+		retry: RetryPolicy{
+			MaxRetries: defaultMaxRetries,
+			BaseDelay:  defaultBackoff,
+		},
+		logger: noopLogger{},
+	}
+	c.httpClient = c.newHTTPClient()
+	return c
+}
+
+// SetLogger injects the Logger used to report request/response
+// activity. The default is a no-op.
+func (s *Client) SetLogger(l Logger) {
+	s.logger = l
+}
+
+// SetTrace toggles logging of the full, unredacted SOAP envelope at
+// debug level. It's off by default because envelopes can carry
+// credentials and session keys; enable it only for trusted,
+// non-production debugging.
+func (s *Client) SetTrace(enabled bool) {
+	s.trace = enabled
+}
+
+// newHTTPClient builds the default transport used by the client. It is
+// built once and reused across calls so that connections to vSphere are
+// pooled and kept alive instead of being torn down on every request.
+func (s *Client) newHTTPClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: s.tls,
+			},
+		},
+	}
+}
+
+// SetTimeout overrides the per-request deadline used by Call.
+// CallContext is not affected: it honors only the deadline on its ctx.
+func (s *Client) SetTimeout(d time.Duration) {
+	s.timeout = d
+}
+
+// SetTLSConfig replaces the TLS configuration used by the client's
+// transport. It only has an effect when that transport is an
+// *http.Transport, as the default one is; a client supplied via
+// SetHTTPClient with any other RoundTripper, or with a nil Transport,
+// is left untouched.
+func (s *Client) SetTLSConfig(cfg *tls.Config) {
+	if tr, ok := s.httpClient.Transport.(*http.Transport); ok {
+		tr.TLSClientConfig = cfg
 	}
 }
 
-var timeout = time.Duration(30 * time.Second)
+// SetHTTPClient lets callers fully control the transport used for
+// requests, including proxies, keepalives and connection pooling.
+func (s *Client) SetHTTPClient(hc *http.Client) {
+	s.httpClient = hc
+}
+
+// HTTPClient returns the *http.Client requests are made with, so
+// callers layering session management on top (see vim/session) can
+// install a cookie jar without discarding the transport/TLS
+// configuration already on the client.
+func (s *Client) HTTPClient() *http.Client {
+	return s.httpClient
+}
 
-func dialTimeout(network, addr string) (net.Conn, error) {
-	return net.DialTimeout(network, addr, timeout)
+// SetRetryPolicy overrides the retry/backoff behavior used by Call.
+func (s *Client) SetRetryPolicy(policy RetryPolicy) {
+	s.retry = policy
 }
 
+// Call is a convenience wrapper around CallContext using a context
+// bound by the client's configured timeout.
 func (s *Client) Call(request interface{}, response interface{}, cookies []*http.Cookie) ([]*http.Cookie, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	return s.CallContext(ctx, request, response, cookies)
+}
+
+// CallContext performs the SOAP request honoring ctx for cancellation
+// and deadlines. Transient network errors and 5xx responses that don't
+// carry a SOAP fault (vSphere signals faults with a 500 status) are
+// retried with exponential backoff according to the client's
+// RetryPolicy.
+func (s *Client) CallContext(ctx context.Context, request interface{}, response interface{}, cookies []*http.Cookie) ([]*http.Cookie, error) {
 	envelope := Envelope{
-		Header:        Header{},
-		EncodingStyle: "http://schemas.xmlsoap.org/soap/encoding/",
+		Header:         Header{},
+		EncodingStyle:  "http://schemas.xmlsoap.org/soap/encoding/",
+		XSINamespace:   xsiNamespace,
+		XSDNamespace:   xsdNamespaceURI,
+		Vim25Namespace: vim25NamespaceURI,
 	}
 
 	reqXml, err := xml.MarshalIndent(request, "  ", "    ")
@@ -82,7 +206,37 @@ func (s *Client) Call(request interface{}, response interface{}, cookies []*http
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", s.url, buffer)
+	body := buffer.Bytes()
+
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		cookiesOut, retryable, err := s.do(ctx, body, cookies, response)
+		if err == nil {
+			return cookiesOut, nil
+		}
+
+		lastErr = err
+		if !retryable || attempt >= s.retry.MaxRetries {
+			return nil, lastErr
+		}
+
+		delay := backoff(s.retry, attempt)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// do performs a single attempt of the SOAP request. retryable reports
+// whether err is worth retrying (transient network error or a 5xx
+// response that isn't a SOAP fault).
+func (s *Client) do(ctx context.Context, body []byte, cookies []*http.Cookie, response interface{}) ([]*http.Cookie, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", s.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, false, err
+	}
 	req.Header.Add("Content-Type", "text/xml; charset=\"utf-8\"")
 	req.Header.Add("SOAPAction", s.soapAction)
 	req.Header.Set("User-Agent", "govsphere/1.0")
@@ -93,50 +247,93 @@ func (s *Client) Call(request interface{}, response interface{}, cookies []*http
 		}
 	}
 
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: s.tls,
-		},
-		Dial: dialTimeout,
-	}
-
-	client := &http.Client{Transport: tr}
+	s.logRequest(req, body)
 
-	rawReq, _ := httputil.DumpRequestOut(req, true)
-	log.Println("===========REQUEST===========")
-	log.Println(string(rawReq) + "\n")
-
-	res, err := client.Do(req)
+	res, err := s.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		s.logger.Errorf("soap: %s %s failed: %s", req.Method, req.URL, err)
+		return nil, true, err
 	}
 	defer res.Body.Close()
 
-	rawRes, _ := httputil.DumpResponse(res, true)
-	log.Println("===========RESPONSE===========")
-	log.Println(string(rawRes) + "\n")
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, false, err
+	}
 
-	body, err := ioutil.ReadAll(res.Body)
+	s.logResponse(res, resBody)
+
+	// vSphere signals SOAP faults with a 500 status, so a 500 is only
+	// retried below once it turns out not to carry a fault.
+	if res.StatusCode >= 500 && res.StatusCode != 500 {
+		return nil, true, fmt.Errorf("soap: unexpected status %s", res.Status)
+	}
 
 	respEnvelope := &Envelope{}
 
-	err = xml.Unmarshal(body, respEnvelope)
+	err = xml.Unmarshal(resBody, respEnvelope)
 	if err != nil {
-		return nil, err
+		return nil, res.StatusCode == 500, err
+	}
+
+	if respEnvelope.Body.Fault != nil {
+		soapErr := newSoapFault(respEnvelope.Body.Fault)
+		s.logger.Warnf("soap: %s", soapErr)
+		return nil, false, soapErr
 	}
 
 	if res.StatusCode == 500 {
-		log.Printf("%#v\n", respEnvelope.Body)
+		return nil, true, fmt.Errorf("soap: http 500 without a parseable fault: %s", res.Status)
 	}
 
 	if response == nil {
-		return res.Cookies(), nil
+		return res.Cookies(), false, nil
 	}
 
 	err = xml.Unmarshal([]byte(respEnvelope.Body.Body), response)
 	if err != nil {
-		return nil, err
+		return nil, false, err
+	}
+
+	return res.Cookies(), false, nil
+}
+
+// logRequest reports an outgoing SOAP request. At the default level the
+// envelope is redacted to strip credentials and session keys; with
+// trace enabled the full envelope is emitted instead.
+func (s *Client) logRequest(req *http.Request, envelope []byte) {
+	soapAction := redactedPlaceholder
+	if s.trace {
+		soapAction = s.soapAction
 	}
+	s.logger.Debugf("soap: request %s %s SOAPAction=%s cookies=%v",
+		req.Method, req.URL, soapAction, redactCookies(req.Cookies()))
 
-	return res.Cookies(), nil
+	if s.trace {
+		s.logger.Debugf("soap: request envelope: %s", envelope)
+	} else {
+		s.logger.Debugf("soap: request envelope (redacted): %s", redact(string(envelope)))
+	}
+}
+
+// logResponse reports an incoming SOAP response, redacting the
+// envelope the same way logRequest does.
+func (s *Client) logResponse(res *http.Response, envelope []byte) {
+	s.logger.Debugf("soap: response status=%s cookies=%v", res.Status, redactCookies(res.Cookies()))
+
+	if s.trace {
+		s.logger.Debugf("soap: response envelope: %s", envelope)
+	} else {
+		s.logger.Debugf("soap: response envelope (redacted): %s", redact(string(envelope)))
+	}
+}
+
+// backoff computes the exponential backoff delay for the given retry
+// attempt (0-indexed), capped at policy.MaxDelay when set.
+func backoff(policy RetryPolicy, attempt int) time.Duration {
+	delay := policy.BaseDelay << uint(attempt)
+	if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+		return policy.MaxDelay
+	}
+	return delay
 }
@@ -0,0 +1,97 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package soap
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond}
+
+	cases := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, 100 * time.Millisecond},
+		{1, 200 * time.Millisecond},
+		{2, 400 * time.Millisecond},
+		{3, 800 * time.Millisecond},
+	}
+
+	for _, c := range cases {
+		if got := backoff(policy, c.attempt); got != c.want {
+			t.Errorf("backoff(attempt=%d) = %s, want %s", c.attempt, got, c.want)
+		}
+	}
+}
+
+func TestBackoffMaxDelay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+
+	if got := backoff(policy, 0); got != 100*time.Millisecond {
+		t.Errorf("backoff(attempt=0) = %s, want 100ms", got)
+	}
+	if got := backoff(policy, 3); got != 300*time.Millisecond {
+		t.Errorf("backoff(attempt=3) = %s, want the 300ms cap", got)
+	}
+}
+
+// capturingLogger records every Debugf call so tests can inspect what
+// the client actually logged.
+type capturingLogger struct {
+	noopLogger
+	lines []string
+}
+
+func (l *capturingLogger) Debugf(format string, args ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+func TestLogRequestRedactsSOAPActionByDefault(t *testing.T) {
+	logger := &capturingLogger{}
+	client := NewClient("https://vsphere.example.com/sdk", "urn:vim25/secretAction", false)
+	client.SetLogger(logger)
+
+	req, err := http.NewRequest("POST", client.url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.logRequest(req, []byte("<Envelope/>"))
+
+	for _, line := range logger.lines {
+		if strings.Contains(line, "secretAction") {
+			t.Fatalf("logRequest leaked the SOAPAction: %q", line)
+		}
+	}
+}
+
+func TestLogRequestIncludesSOAPActionWhenTracing(t *testing.T) {
+	logger := &capturingLogger{}
+	client := NewClient("https://vsphere.example.com/sdk", "urn:vim25/secretAction", false)
+	client.SetLogger(logger)
+	client.SetTrace(true)
+
+	req, err := http.NewRequest("POST", client.url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.logRequest(req, []byte("<Envelope/>"))
+
+	found := false
+	for _, line := range logger.lines {
+		if strings.Contains(line, "secretAction") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("logRequest did not include the SOAPAction with trace enabled")
+	}
+}
@@ -0,0 +1,138 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package soap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// xsiNamespace is the namespace vSphere puts xsi:type attributes in.
+const xsiNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+
+// TypeRegistry maps the xsi:type names vSphere puts on polymorphic
+// elements (e.g. "VirtualDisk" on a VirtualDevice-typed field) to the
+// concrete Go type generated for them. Generated mo/do types register
+// themselves here, typically from an init func:
+//
+//	func init() { soap.Types.Register("VirtualDisk", VirtualDisk{}) }
+type TypeRegistry struct {
+	mu    sync.RWMutex
+	byXSI map[string]reflect.Type
+	byGo  map[reflect.Type]string
+}
+
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{
+		byXSI: make(map[string]reflect.Type),
+		byGo:  make(map[reflect.Type]string),
+	}
+}
+
+// Types is the registry the generated mo/do packages populate and that
+// EncodeTyped/DecodeTyped consult.
+var Types = NewTypeRegistry()
+
+// Register associates an xsi:type name with zero's Go type.
+func (r *TypeRegistry) Register(xsiType string, zero interface{}) {
+	t := reflect.TypeOf(zero)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.byXSI[xsiType] = t
+	r.byGo[t] = xsiType
+}
+
+// New constructs a new, addressable zero value of the Go type
+// registered under xsiType.
+func (r *TypeRegistry) New(xsiType string) (interface{}, error) {
+	r.mu.RLock()
+	t, ok := r.byXSI[xsiType]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("soap: no type registered for xsi:type %q", xsiType)
+	}
+	return reflect.New(t).Interface(), nil
+}
+
+// NameOf returns the xsi:type name registered for v's concrete type.
+func (r *TypeRegistry) NameOf(v interface{}) (string, bool) {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name, ok := r.byGo[t]
+	return name, ok
+}
+
+// EncodeTyped encodes v under start, adding an xsi:type attribute
+// naming v's concrete type as registered in Types. Generated MarshalXML
+// methods for interface-typed fields (e.g. VirtualDevice) call this
+// instead of e.EncodeElement directly, so the concrete subclass (e.g.
+// VirtualDisk) survives the round trip. nsPrefix is the namespace
+// prefix the xsi:type value is qualified with, typically "vim25".
+func EncodeTyped(e *xml.Encoder, start xml.StartElement, v interface{}, nsPrefix string) error {
+	xsiType, ok := Types.NameOf(v)
+	if !ok {
+		return fmt.Errorf("soap: %T is not registered with an xsi:type", v)
+	}
+
+	if nsPrefix != "" {
+		xsiType = nsPrefix + ":" + xsiType
+	}
+	start.Attr = append(start.Attr, xml.Attr{
+		Name:  xml.Name{Local: "xsi:type"},
+		Value: xsiType,
+	})
+
+	return e.EncodeElement(v, start)
+}
+
+// DecodeTyped reads the xsi:type attribute off start, constructs the
+// matching Go type via Types and decodes start's element into it. The
+// returned value is what a generated UnmarshalXML method assigns to its
+// interface-typed field.
+func DecodeTyped(d *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	xsiType := xsiTypeOf(start)
+	if xsiType == "" {
+		return nil, fmt.Errorf("soap: element %s has no xsi:type attribute", start.Name.Local)
+	}
+
+	v, err := Types.New(xsiType)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.DecodeElement(v, &start); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// xsiTypeOf extracts the local name out of a start element's xsi:type
+// attribute, stripping whatever namespace prefix it was qualified with.
+func xsiTypeOf(start xml.StartElement) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Local != "type" {
+			continue
+		}
+		if attr.Name.Space != xsiNamespace && attr.Name.Space != "xsi" {
+			continue
+		}
+
+		value := attr.Value
+		if i := strings.IndexByte(value, ':'); i >= 0 {
+			value = value[i+1:]
+		}
+		return value
+	}
+	return ""
+}
@@ -0,0 +1,84 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+type testWidget struct {
+	Name string `xml:"name"`
+}
+
+func TestTypeRegistryRegisterNewNameOf(t *testing.T) {
+	r := NewTypeRegistry()
+	r.Register("Widget", testWidget{})
+
+	v, err := r.New("Widget")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := v.(*testWidget); !ok {
+		t.Fatalf("New(%q) returned %T, want *testWidget", "Widget", v)
+	}
+
+	name, ok := r.NameOf(&testWidget{})
+	if !ok || name != "Widget" {
+		t.Fatalf("NameOf(&testWidget{}) = (%q, %v), want (Widget, true)", name, ok)
+	}
+}
+
+func TestTypeRegistryNewUnregistered(t *testing.T) {
+	r := NewTypeRegistry()
+	if _, err := r.New("DoesNotExist"); err == nil {
+		t.Fatal("expected an error for an unregistered xsi:type")
+	}
+}
+
+func TestEncodeDecodeTypedRoundTrip(t *testing.T) {
+	Types.Register("TestWidget", testWidget{})
+
+	var buf bytes.Buffer
+	e := xml.NewEncoder(&buf)
+	start := xml.StartElement{Name: xml.Name{Local: "device"}}
+	if err := EncodeTyped(e, start, &testWidget{Name: "gadget"}, "vim25"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	d := xml.NewDecoder(&buf)
+	tok, err := d.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decodedStart, ok := tok.(xml.StartElement)
+	if !ok {
+		t.Fatalf("expected a start element, got %T", tok)
+	}
+
+	v, err := DecodeTyped(d, decodedStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	widget, ok := v.(*testWidget)
+	if !ok {
+		t.Fatalf("DecodeTyped returned %T, want *testWidget", v)
+	}
+	if widget.Name != "gadget" {
+		t.Errorf("widget.Name = %q, want gadget", widget.Name)
+	}
+}
+
+func TestDecodeTypedMissingXSIType(t *testing.T) {
+	start := xml.StartElement{Name: xml.Name{Local: "device"}}
+	d := xml.NewDecoder(bytes.NewReader([]byte(`<device></device>`)))
+	if _, err := DecodeTyped(d, start); err == nil {
+		t.Fatal("expected an error when the element has no xsi:type attribute")
+	}
+}
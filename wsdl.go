@@ -0,0 +1,385 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// wsdlDefinitions is the root element of a WSDL document, trimmed down
+// to the pieces needed to drive code generation: the embedded XSD
+// schema describing the vim25 types and the RPC surface describing
+// which managed object invokes which operation.
+type wsdlDefinitions struct {
+	XMLName  xml.Name      `xml:"definitions"`
+	Types    wsdlTypes     `xml:"types"`
+	Messages []wsdlMessage `xml:"message"`
+	PortType wsdlPortType  `xml:"portType"`
+	Binding  wsdlBinding   `xml:"binding"`
+}
+
+type wsdlTypes struct {
+	Schemas []xsdSchema `xml:"schema"`
+}
+
+type wsdlMessage struct {
+	Name string      `xml:"name,attr"`
+	Part wsdlMsgPart `xml:"part"`
+}
+
+type wsdlMsgPart struct {
+	Name    string `xml:"name,attr"`
+	Element string `xml:"element,attr"`
+}
+
+type wsdlPortType struct {
+	Operations []wsdlOperation `xml:"operation"`
+}
+
+type wsdlOperation struct {
+	Name   string        `xml:"name,attr"`
+	Input  wsdlIOBinding `xml:"input"`
+	Output wsdlIOBinding `xml:"output"`
+}
+
+type wsdlIOBinding struct {
+	Message string `xml:"message,attr"`
+}
+
+type wsdlBinding struct {
+	Operations []wsdlBindingOperation `xml:"operation"`
+}
+
+type wsdlBindingOperation struct {
+	Name string `xml:"name,attr"`
+}
+
+// xsdSchema is one <xsd:schema>, either inlined in the WSDL's <types>
+// or pulled in via xsd:import/xsd:include.
+type xsdSchema struct {
+	TargetNamespace string           `xml:"targetNamespace,attr"`
+	Imports         []xsdImport      `xml:"import"`
+	Includes        []xsdImport      `xml:"include"`
+	ComplexTypes    []xsdComplexType `xml:"complexType"`
+	SimpleTypes     []xsdSimpleType  `xml:"simpleType"`
+	Elements        []xsdElement     `xml:"element"`
+}
+
+type xsdImport struct {
+	Namespace      string `xml:"namespace,attr"`
+	SchemaLocation string `xml:"schemaLocation,attr"`
+}
+
+type xsdComplexType struct {
+	Name           string       `xml:"name,attr"`
+	Sequence       []xsdElement `xml:"sequence>element"`
+	ComplexContent *xsdContent  `xml:"complexContent"`
+}
+
+// xsdContent models complexContent's extension or restriction, which
+// is how the WSDL expresses the "extends" chain that lookUpNamespace
+// threads through the generated types. simpleContent is not decoded.
+type xsdContent struct {
+	Extension   *xsdDerivation `xml:"extension"`
+	Restriction *xsdDerivation `xml:"restriction"`
+}
+
+type xsdDerivation struct {
+	Base     string       `xml:"base,attr"`
+	Sequence []xsdElement `xml:"sequence>element"`
+}
+
+type xsdSimpleType struct {
+	Name        string         `xml:"name,attr"`
+	Enumeration []xsdEnumValue `xml:"restriction>enumeration"`
+}
+
+type xsdEnumValue struct {
+	Value string `xml:"value,attr"`
+}
+
+type xsdElement struct {
+	Name      string `xml:"name,attr"`
+	Type      string `xml:"type,attr"`
+	MinOccurs string `xml:"minOccurs,attr"`
+	MaxOccurs string `xml:"maxOccurs,attr"`
+}
+
+// loadWSDL reads a WSDL file and every schema it reaches via
+// xsd:import/xsd:include, resolved relative to the WSDL's own
+// directory, which is how vSphere ships vimService.wsdl alongside its
+// vim.xsd and core-types.xsd siblings.
+func loadWSDL(path string) (*wsdlDefinitions, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defs := &wsdlDefinitions{}
+	if err := xml.Unmarshal(raw, defs); err != nil {
+		return nil, fmt.Errorf("wsdl: %s: %s", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	seen := map[string]bool{path: true}
+
+	var resolve func(schemas []xsdSchema) error
+	resolve = func(schemas []xsdSchema) error {
+		for _, schema := range schemas {
+			for _, ref := range append(append([]xsdImport{}, schema.Imports...), schema.Includes...) {
+				if ref.SchemaLocation == "" {
+					continue
+				}
+
+				loc := filepath.Join(dir, ref.SchemaLocation)
+				if seen[loc] {
+					continue
+				}
+				seen[loc] = true
+
+				raw, err := ioutil.ReadFile(loc)
+				if err != nil {
+					return fmt.Errorf("wsdl: resolving %s: %s", ref.SchemaLocation, err)
+				}
+
+				included := &xsdSchema{}
+				if err := xml.Unmarshal(raw, included); err != nil {
+					return fmt.Errorf("wsdl: %s: %s", loc, err)
+				}
+
+				defs.Types.Schemas = append(defs.Types.Schemas, *included)
+				if err := resolve([]xsdSchema{*included}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := resolve(defs.Types.Schemas); err != nil {
+		return nil, err
+	}
+
+	return defs, nil
+}
+
+func (d *wsdlDefinitions) messageByName(name string) (wsdlMessage, bool) {
+	name = stripns(name)
+	for _, msg := range d.Messages {
+		if msg.Name == name {
+			return msg, true
+		}
+	}
+	return wsdlMessage{}, false
+}
+
+// wsdlToObjects walks the parsed WSDL/XSD and builds the []Object that
+// the rest of the generator (genCode and its mo/do/enum/fault
+// templates) already knows how to render, resolving xsd:extension and
+// xsd:restriction into the "extends" chain lookUpNamespace uses and
+// turning each portType operation into a method stub on its owning
+// managed object.
+//
+// This replaces hand-maintaining the JSON apiDef file: pointing
+// wsdlToObjects at vSphere's vimService.wsdl directly keeps bindings in
+// sync with whatever API version (5.5 through 8.x) shipped it.
+func wsdlToObjects(defs *wsdlDefinitions) ([]Object, error) {
+	// owners maps a request wrapper type (e.g. "RetrieveProperties") to
+	// the managed object type named by its "_this" field (e.g.
+	// "PropertyCollector"): that's how a vim25 operation is tied back to
+	// the managed object that owns it, since the portType operation name
+	// only identifies the request/response wrapper types, not the mo.
+	owners := requestOwners(defs.Types.Schemas)
+
+	moTypes := make(map[string]bool, len(owners))
+	for _, owner := range owners {
+		moTypes[owner] = true
+	}
+
+	methodsByOwner := make(map[string][]Method)
+	for _, op := range defs.PortType.Operations {
+		reqMsg, ok := defs.messageByName(op.Input.Message)
+		if !ok {
+			continue
+		}
+		reqType := stripns(reqMsg.Part.Element)
+
+		owner, ok := owners[reqType]
+		if !ok {
+			continue
+		}
+
+		methodsByOwner[owner] = append(methodsByOwner[owner], methodFromOperation(defs, op, reqType))
+	}
+
+	var objects []Object
+
+	for _, schema := range defs.Types.Schemas {
+		for _, ct := range schema.ComplexTypes {
+			obj := Object{
+				Name:      ct.Name,
+				Namespace: namespaceFor(ct.Name, moTypes),
+				Methods:   methodsByOwner[ct.Name],
+			}
+
+			fields := ct.Sequence
+			if ct.ComplexContent != nil {
+				deriv := ct.ComplexContent.Extension
+				if deriv == nil {
+					deriv = ct.ComplexContent.Restriction
+				}
+				if deriv != nil {
+					obj.Extends = stripns(deriv.Base)
+					fields = append(fields, deriv.Sequence...)
+				}
+			}
+
+			for _, f := range fields {
+				// The "_this" element's type names the owning managed
+				// object (see requestOwners above), but the value
+				// actually sent on the wire is always a generic
+				// ManagedObjectReference; rendering it as the mo type
+				// itself would make "do" import "mo" right back, a
+				// cycle with "mo" importing "do" for exactly that type.
+				fieldType := f.Type
+				if f.Name == "_this" {
+					fieldType = "ManagedObjectReference"
+				}
+
+				obj.Fields = append(obj.Fields, Field{
+					Name:     f.Name,
+					Type:     toGoType(fieldType),
+					Optional: f.MinOccurs == "0",
+					Slice:    f.MaxOccurs == "unbounded",
+				})
+			}
+
+			objects = append(objects, obj)
+		}
+
+		for _, st := range schema.SimpleTypes {
+			if len(st.Enumeration) == 0 {
+				continue
+			}
+
+			obj := Object{
+				Name:      st.Name,
+				Namespace: "enum",
+			}
+			for _, v := range st.Enumeration {
+				obj.Fields = append(obj.Fields, Field{Name: v.Value, Type: "string"})
+			}
+			objects = append(objects, obj)
+		}
+	}
+
+	return objects, nil
+}
+
+// requestOwners maps each request wrapper complex type (e.g.
+// "RetrieveProperties") to the managed object type named by its
+// "_this" field (e.g. "PropertyCollector"). A request element's name
+// matches the operation name, but it's the "_this" parameter that
+// names the managed object the operation is actually a method of.
+func requestOwners(schemas []xsdSchema) map[string]string {
+	owners := make(map[string]string)
+	for _, schema := range schemas {
+		for _, ct := range schema.ComplexTypes {
+			for _, f := range ct.Sequence {
+				if f.Name == "_this" {
+					owners[ct.Name] = stripns(f.Type)
+					break
+				}
+			}
+		}
+	}
+	return owners
+}
+
+// methodFromOperation turns a portType operation into the Method stub
+// the mo template renders as a func that marshals its request wrapper
+// and calls soap.Client.Call. requestType is the request wrapper's
+// element name, already resolved by the caller via requestOwners.
+func methodFromOperation(defs *wsdlDefinitions, op wsdlOperation, requestType string) Method {
+	m := Method{Name: op.Name, RequestType: requestType}
+
+	if msg, ok := defs.messageByName(op.Output.Message); ok {
+		m.ReturnValue = stripns(msg.Part.Element)
+	}
+
+	return m
+}
+
+// namespaceFor buckets a vim25 type name into one of the mo/do/enum/
+// fault packages: moTypes are the managed object types identified by
+// requestOwners, *Fault types go to fault using the same naming
+// convention the hand-curated apiDef JSON used, and everything else is
+// a plain data object.
+func namespaceFor(typeName string, moTypes map[string]bool) string {
+	if moTypes[typeName] {
+		return "mo"
+	}
+	if len(typeName) > 5 && typeName[len(typeName)-5:] == "Fault" {
+		return "fault"
+	}
+	return "do"
+}
+
+// generateFromWSDL is the WSDL-driven counterpart to generate: instead
+// of reading a pre-built apiDef JSON file, it parses wsdlFile (and
+// whatever xsd:import/xsd:include it pulls in) and feeds the resulting
+// []Object through the same mo/do/enum/fault templates.
+func generateFromWSDL(wsdlFile string) {
+	defs, err := loadWSDL(wsdlFile)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	objects, err := wsdlToObjects(defs)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	objnsmap = make(map[string]string)
+	for _, obj := range objects {
+		objnsmap[obj.Name] = obj.Namespace
+	}
+
+	mainPkg := "./vim"
+	os.Mkdir(mainPkg, 0744)
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		genCode(objects, mainPkg, moTmpl, "mo")
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		genCode(objects, mainPkg, doTmpl, "do")
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		genCode(objects, mainPkg, enumTmpl, "enum")
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		genCode(objects, mainPkg, faultTmpl, "fault")
+	}()
+
+	wg.Wait()
+}
@@ -0,0 +1,209 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fixtureWSDL is a small, vim25-shaped WSDL: one managed object
+// (PropertyCollector) with one operation (RetrieveProperties) whose
+// request wrapper's "_this" parameter identifies PropertyCollector as
+// the owner, plus the data objects that operation's request/response
+// reference.
+const fixtureWSDL = `<?xml version="1.0" encoding="UTF-8"?>
+<definitions name="vim" targetNamespace="urn:vim25"
+	xmlns:tns="urn:vim25"
+	xmlns="http://schemas.xmlsoap.org/wsdl/">
+	<types>
+		<xsd:schema targetNamespace="urn:vim25" xmlns:xsd="http://www.w3.org/2001/XMLSchema">
+			<xsd:complexType name="ManagedObjectReference">
+				<xsd:sequence/>
+			</xsd:complexType>
+			<xsd:complexType name="PropertyCollector">
+				<xsd:sequence>
+					<xsd:element name="Ref" type="ManagedObjectReference"/>
+				</xsd:sequence>
+			</xsd:complexType>
+			<xsd:complexType name="PropertyFilterSpec">
+				<xsd:sequence>
+					<xsd:element name="pathSet" type="xsd:string" maxOccurs="unbounded"/>
+				</xsd:sequence>
+			</xsd:complexType>
+			<xsd:complexType name="ObjectContent">
+				<xsd:sequence>
+					<xsd:element name="obj" type="ManagedObjectReference"/>
+				</xsd:sequence>
+			</xsd:complexType>
+			<xsd:complexType name="RetrieveProperties">
+				<xsd:sequence>
+					<xsd:element name="_this" type="PropertyCollector"/>
+					<xsd:element name="specSet" type="PropertyFilterSpec" maxOccurs="unbounded"/>
+				</xsd:sequence>
+			</xsd:complexType>
+			<xsd:complexType name="RetrievePropertiesResponse">
+				<xsd:sequence>
+					<xsd:element name="returnval" type="ObjectContent" maxOccurs="unbounded"/>
+				</xsd:sequence>
+			</xsd:complexType>
+		</xsd:schema>
+	</types>
+	<message name="RetrievePropertiesRequestMsg">
+		<part name="parameters" element="tns:RetrieveProperties"/>
+	</message>
+	<message name="RetrievePropertiesResponseMsg">
+		<part name="parameters" element="tns:RetrievePropertiesResponse"/>
+	</message>
+	<portType name="VimPortType">
+		<operation name="RetrieveProperties">
+			<input message="tns:RetrievePropertiesRequestMsg"/>
+			<output message="tns:RetrievePropertiesResponseMsg"/>
+		</operation>
+	</portType>
+</definitions>
+`
+
+func findObject(t *testing.T, objects []Object, name string) Object {
+	t.Helper()
+	for _, obj := range objects {
+		if obj.Name == name {
+			return obj
+		}
+	}
+	t.Fatalf("no object named %q in %+v", name, objects)
+	return Object{}
+}
+
+// TestWsdlToObjectsResolvesManagedObjects checks that a WSDL operation
+// ends up as a method on the managed object its request's "_this"
+// parameter names, not on the request/response data objects or
+// nowhere at all.
+func TestWsdlToObjectsResolvesManagedObjects(t *testing.T) {
+	wsdlPath := filepath.Join(t.TempDir(), "vim.wsdl")
+	if err := ioutil.WriteFile(wsdlPath, []byte(fixtureWSDL), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defs, err := loadWSDL(wsdlPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objects, err := wsdlToObjects(defs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pc := findObject(t, objects, "PropertyCollector")
+	if pc.Namespace != "mo" {
+		t.Errorf("PropertyCollector.Namespace = %q, want mo", pc.Namespace)
+	}
+	if len(pc.Methods) != 1 {
+		t.Fatalf("PropertyCollector.Methods = %+v, want exactly one method", pc.Methods)
+	}
+	if m := pc.Methods[0]; m.Name != "RetrieveProperties" || m.RequestType != "RetrieveProperties" || m.ReturnValue != "RetrievePropertiesResponse" {
+		t.Errorf("PropertyCollector.Methods[0] = %+v, want RetrieveProperties(RetrieveProperties) RetrievePropertiesResponse", m)
+	}
+
+	req := findObject(t, objects, "RetrieveProperties")
+	if req.Namespace != "do" {
+		t.Errorf("RetrieveProperties.Namespace = %q, want do", req.Namespace)
+	}
+	if len(req.Methods) != 0 {
+		t.Errorf("RetrieveProperties.Methods = %+v, want none: the method belongs on the owning mo, not the request wrapper", req.Methods)
+	}
+}
+
+// TestGeneratedCodeCompiles renders the fixture's objects through the
+// real mo/do/enum/fault templates and builds the result against the
+// actual vim/soap package, to catch the kind of undefined-symbol/
+// unused-import breakage that isn't visible from wsdlToObjects alone.
+func TestGeneratedCodeCompiles(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go toolchain not on PATH")
+	}
+
+	wsdlPath := filepath.Join(t.TempDir(), "vim.wsdl")
+	if err := ioutil.WriteFile(wsdlPath, []byte(fixtureWSDL), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defs, err := loadWSDL(wsdlPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objects, err := wsdlToObjects(defs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	mainPkg := filepath.Join(root, "vim")
+	if err := os.MkdirAll(mainPkg, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	genCode(objects, mainPkg, moTmpl, "mo")
+	genCode(objects, mainPkg, doTmpl, "do")
+	genCode(objects, mainPkg, enumTmpl, "enum")
+	genCode(objects, mainPkg, faultTmpl, "fault")
+
+	moSource, err := ioutil.ReadFile(filepath.Join(mainPkg, "mo", "mo.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(moSource), "func (m *PropertyCollector) RetrieveProperties(") {
+		t.Fatalf("generated mo.go is missing the RetrieveProperties method stub:\n%s", moSource)
+	}
+
+	if err := copyDir("vim/soap", filepath.Join(mainPkg, "soap")); err != nil {
+		t.Fatal(err)
+	}
+
+	goMod := "module github.com/c4milo/govsphere\n\ngo 1.21\n"
+	if err := ioutil.WriteFile(filepath.Join(root, "go.mod"), []byte(goMod), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("go", "build", "./...")
+	cmd.Dir = root
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("generated code does not compile: %s\n%s", err, out)
+	}
+}
+
+// copyDir copies the .go files of a directory tree rooted at src into
+// dst, creating dst's directory structure as needed.
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(target, contents, 0644)
+	})
+}